@@ -1,8 +1,14 @@
 package rpc
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
 
 	gethrpc "github.com/ethereum/go-ethereum/rpc"
 )
@@ -10,6 +16,17 @@ import (
 const (
 	jsonrpcVersion        = "2.0"
 	errInvalidMessageCode = -32700 // from go-ethereum/rpc/errors.go
+	errInvalidRequestCode = -32600 // from go-ethereum/rpc/errors.go
+	errInternalCode       = -32603 // from JSON-RPC 2.0 specification
+
+	// maxMessageDepth limits nesting of arrays and objects in a JSON-RPC body.
+	// No legitimate request gets close to it.
+	maxMessageDepth = 64
+)
+
+var (
+	errMessageTooDeep = errors.New("JSON-RPC message is nested too deeply")
+	errInvalidVersion = errors.New(`invalid request: jsonrpc must be "2.0"`)
 )
 
 // for JSON-RPC responses obtained via CallRaw(), we have no way
@@ -24,6 +41,14 @@ var defaultMsgID = json.RawMessage(`0`)
 // returns string in JSON format with response (successul or error).
 func (c *Client) CallRaw(body string) string {
 	ctx := context.Background()
+	return c.CallRawContext(ctx, body)
+}
+
+// CallRawContext performs a JSON-RPC call with already crafted JSON-RPC body
+// and given context. If the context is canceled or its deadline is exceeded
+// before the call has returned, the response carries an internal error with
+// the context error as a message instead of a result.
+func (c *Client) CallRawContext(ctx context.Context, body string) string {
 	return c.callRawContext(ctx, json.RawMessage(body))
 }
 
@@ -67,6 +92,10 @@ type jsonError struct {
 // either by changing exported API (provide only Call, not CallRaw) or
 // refactoring go-ethereum's client to allow using raw JSON directly.
 func (c *Client) callRawContext(ctx context.Context, body json.RawMessage) string {
+	if err := checkMessageDepth(body, maxMessageDepth); err != nil {
+		return newErrorResponse(errInvalidMessageCode, err, defaultMsgID)
+	}
+
 	if isBatch(body) {
 		return c.callBatchMethods(ctx, body)
 	}
@@ -92,9 +121,27 @@ func (c *Client) callBatchMethods(ctx context.Context, msgs json.RawMessage) str
 	// run all methods sequentially, this seems to be main
 	// objective to use batched requests.
 	// See: https://github.com/ethereum/wiki/wiki/JavaScript-API#batch-requests
+	//
+	// Once the connection to the node is lost, there is no point in
+	// calling it for the rest of the batch. The failed request and all
+	// valid requests after it are answered with ErrConnectionLost.
 	responses := make([]json.RawMessage, len(requests))
+	connectionLost := false
 	for i := range requests {
-		resp := c.callSingleMethod(ctx, requests[i])
+		method, params, id, resp, err := c.parseSingleMethod(requests[i])
+		switch {
+		case err != nil:
+			// invalid requests are answered with their own errors
+		case connectionLost:
+			resp = newErrorResponse(errInternalCode, ErrConnectionLost, id)
+		default:
+			resp, err = c.execMethod(ctx, method, params, id)
+			if isConnectionError(err) {
+				c.log.Warn("Connection lost during batch call", "method", method, "error", err)
+				connectionLost = true
+				resp = newErrorResponse(errInternalCode, ErrConnectionLost, id)
+			}
+		}
 		responses[i] = json.RawMessage(resp)
 	}
 
@@ -109,29 +156,131 @@ func (c *Client) callBatchMethods(ctx context.Context, msgs json.RawMessage) str
 
 // callSingleMethod executes single JSON-RPC message and constructs proper response.
 func (c *Client) callSingleMethod(ctx context.Context, msg json.RawMessage) string {
+	method, params, id, resp, err := c.parseSingleMethod(msg)
+	if err != nil {
+		return resp
+	}
+
+	resp, _ = c.execMethod(ctx, method, params, id)
+	return resp
+}
+
+// parseSingleMethod unmarshals and validates single JSON-RPC message.
+// If the message is invalid, it returns the error response to answer it with
+// along with the error.
+func (c *Client) parseSingleMethod(msg json.RawMessage) (string, []interface{}, json.RawMessage, string, error) {
 	// unmarshal JSON body into json-rpc request
-	method, params, id, err := methodAndParamsFromBody(msg)
+	req, err := unmarshalMessage(msg)
 	if err != nil {
-		return newErrorResponse(errInvalidMessageCode, err, id)
+		// ID is still set if only other fields have wrong types
+		return "", nil, req.ID, newErrorResponse(errInvalidMessageCode, err, req.ID), err
+	}
+
+	if err := c.checkVersion(req.Version); err != nil {
+		return "", nil, req.ID, newErrorResponse(errInvalidRequestCode, err, req.ID), err
+	}
+
+	method, params, id, err := methodAndParamsFromMessage(req)
+	if err != nil {
+		return "", nil, id, newErrorResponse(errInvalidMessageCode, err, id), err
+	}
+
+	return method, params, id, "", nil
+}
+
+// execMethod executes JSON-RPC method and constructs proper response.
+// It also returns the error of the underlying call, if any, so that callers
+// can react to failures not visible in the JSON response.
+func (c *Client) execMethod(ctx context.Context, method string, params []interface{}, id json.RawMessage) (string, error) {
+	c.RLock()
+	timeout := c.callRawTimeout
+	c.RUnlock()
+
+	parent := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
 	// route and execute
 	var result json.RawMessage
-	err = c.CallContext(ctx, &result, method, params...)
+	err := c.CallContext(ctx, &result, method, params...)
+
+	// the caller gave up on the call
+	if err != nil && parent.Err() != nil {
+		err = parent.Err()
+		return newErrorResponse(errInternalCode, err, id), err
+	}
+
+	// the error itself can't be used here, as it may come
+	// wrapped, e.g. into *url.Error by the HTTP transport
+	if err != nil && timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("%s call timed out after %s", method, timeout)
+		return newErrorResponse(errInternalCode, err, id), err
+	}
 
 	// as we have to return original JSON, we have to
 	// analyze returned error and reconstruct original
 	// JSON error response.
 	if err != nil && err != gethrpc.ErrNoResult {
 		if er, ok := err.(gethrpc.Error); ok {
-			return newErrorResponse(er.ErrorCode(), err, id)
+			return newErrorResponse(er.ErrorCode(), err, id), err
 		}
 
-		return newErrorResponse(errInvalidMessageCode, err, id)
+		return newErrorResponse(errInvalidMessageCode, err, id), err
+	}
+
+	if err := c.validateResult(method, result); err != nil {
+		return newErrorResponse(errInternalCode, err, id), err
 	}
 
 	// finally, marshal answer
-	return newSuccessResponse(result, id)
+	return newSuccessResponse(result, id), nil
+}
+
+// checkVersion returns errInvalidVersion if strict version checking
+// is enabled and version is not the JSON-RPC 2.0 one.
+func (c *Client) checkVersion(version string) error {
+	c.RLock()
+	strict := c.strictVersion
+	c.RUnlock()
+
+	if strict && version != jsonrpcVersion {
+		return errInvalidVersion
+	}
+
+	return nil
+}
+
+// isConnectionError returns true if err means that the connection
+// to the node is gone, rather than that a particular call failed.
+// Timeouts and cancellations are caused by the caller and don't count.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if err == gethrpc.ErrClientQuit || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	var (
+		opErr  *net.OpError
+		urlErr *url.Error
+	)
+	if errors.As(err, &opErr) {
+		return !opErr.Timeout()
+	}
+	if errors.As(err, &urlErr) {
+		return !urlErr.Timeout()
+	}
+
+	return false
 }
 
 // methodAndParamsFromBody extracts Method and Params of
@@ -144,11 +293,18 @@ func methodAndParamsFromBody(body json.RawMessage) (string, []interface{}, json.
 		return "", nil, nil, err
 	}
 
+	return methodAndParamsFromMessage(msg)
+}
+
+// methodAndParamsFromMessage is the same as methodAndParamsFromBody,
+// but for already unmarshalled JSON-RPC message.
+func methodAndParamsFromMessage(msg *jsonrpcRequest) (string, []interface{}, json.RawMessage, error) {
 	params := []interface{}{}
 	if msg.Params != nil {
-		err = json.Unmarshal(msg.Params, &params)
+		err := json.Unmarshal(msg.Params, &params)
 		if err != nil {
-			return "", nil, nil, err
+			// keep the ID so the caller can correlate the error response
+			return "", nil, msg.ID, err
 		}
 	}
 
@@ -202,6 +358,31 @@ func newErrorResponse(code int, err error, id json.RawMessage) string {
 	return string(data)
 }
 
+// checkMessageDepth returns errMessageTooDeep if arrays and objects in msg
+// are nested deeper than maxDepth. The body is read token by token, so
+// no recursion happens regardless of the input. Syntax errors are
+// ignored here and reported by the unmarshalling that follows.
+func checkMessageDepth(msg json.RawMessage, maxDepth int) error {
+	dec := json.NewDecoder(bytes.NewReader(msg))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > maxDepth {
+				return errMessageTooDeep
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
 // isBatch returns true when the first non-whitespace characters is '['
 // code from go-ethereum's rpc client (rpc/client.go)
 func isBatch(msg json.RawMessage) bool {
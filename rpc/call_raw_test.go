@@ -1,11 +1,24 @@
 package rpc
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/status-im/status-go/params"
 )
 
 func TestNewSuccessResponse(t *testing.T) {
@@ -96,6 +109,14 @@ func TestMethodAndParamsFromBody(t *testing.T) {
 			json.RawMessage(`44`),
 			false,
 		},
+		{
+			"params_not_array",
+			json.RawMessage(`{"jsonrpc": "2.0", "id": 42, "method": "test", "params": {"key": "value"}}`),
+			[]interface{}{},
+			"",
+			json.RawMessage(`42`),
+			true,
+		},
 		{
 			"getFilterMessage_array",
 			json.RawMessage(`[{"jsonrpc":"2.0","id":44,"method":"shh_getFilterMessages","params":["3de6a8867aeb75be74d68478b853b4b0e063704d30f8231c45d0fcbd97af207e"]}]`),
@@ -119,6 +140,7 @@ func TestMethodAndParamsFromBody(t *testing.T) {
 			method, params, id, err := methodAndParamsFromBody(test.body)
 			if test.shouldFail {
 				require.Error(t, err)
+				require.EqualValues(t, test.id, id)
 				return
 			}
 			require.NoError(t, err)
@@ -129,6 +151,228 @@ func TestMethodAndParamsFromBody(t *testing.T) {
 	}
 }
 
+func TestCheckMessageDepth(t *testing.T) {
+	nested := func(depth int) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"eth_call","params":` +
+			strings.Repeat(`[`, depth) + strings.Repeat(`]`, depth) + `}`)
+	}
+
+	cases := []struct {
+		name     string
+		body     json.RawMessage
+		expected error
+	}{
+		{"flat", json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`), nil},
+		{"at_limit", nested(maxMessageDepth - 1), nil},
+		{"over_limit", nested(maxMessageDepth), errMessageTooDeep},
+		{"batch_over_limit", json.RawMessage(`[` + string(nested(maxMessageDepth)) + `]`), errMessageTooDeep},
+		{"invalid", json.RawMessage(`{"jsonrpc":`), nil},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, checkMessageDepth(test.body, maxMessageDepth))
+		})
+	}
+}
+
+func TestCallRawMessageTooDeep(t *testing.T) {
+	c, err := NewClient(nil, params.UpstreamRPCConfig{})
+	require.NoError(t, err)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[` + strings.Repeat(`{"a":`, 1000) + `1` + strings.Repeat(`}`, 1000) + `]}`
+	expected := fmt.Sprintf(`{"jsonrpc":"2.0","id":0,"error":{"code":-32700,"message":"%s"}}`, errMessageTooDeep)
+	require.Equal(t, expected, c.CallRaw(body))
+}
+
+func TestCallRawStrictVersion(t *testing.T) {
+	c, err := NewClient(nil, params.UpstreamRPCConfig{})
+	require.NoError(t, err)
+
+	c.RegisterHandler("eth_blockNumber", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return "0x1", nil
+	})
+
+	cases := []struct {
+		name     string
+		body     string
+		strict   bool
+		expected string
+	}{
+		{"lenient_v1", `{"jsonrpc":"1.0","id":1,"method":"eth_blockNumber"}`, false, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`},
+		{"lenient_missing", `{"id":1,"method":"eth_blockNumber"}`, false, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`},
+		{"strict_v2", `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`, true, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`},
+		{"strict_v1", `{"jsonrpc":"1.0","id":1,"method":"eth_blockNumber"}`, true, `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"invalid request: jsonrpc must be \"2.0\""}}`},
+		{"strict_missing", `{"id":1,"method":"eth_blockNumber"}`, true, `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"invalid request: jsonrpc must be \"2.0\""}}`},
+		{"strict_v1_bad_params", `{"jsonrpc":"1.0","id":1,"method":"eth_blockNumber","params":{}}`, true, `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"invalid request: jsonrpc must be \"2.0\""}}`},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			c.SetStrictJSONRPCVersion(test.strict)
+			require.Equal(t, test.expected, c.CallRaw(test.body))
+		})
+	}
+}
+
+func TestCallRawTimeout(t *testing.T) {
+	c, err := NewClient(nil, params.UpstreamRPCConfig{})
+	require.NoError(t, err)
+
+	c.RegisterHandler("eth_syncing", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	c.RegisterHandler("eth_blockNumber", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return "0x1", nil
+	})
+	c.SetCallRawTimeout(10 * time.Millisecond)
+
+	got := c.CallRaw(`{"jsonrpc":"2.0","id":1,"method":"eth_syncing","params":[]}`)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"eth_syncing call timed out after 10ms"}}`, got)
+
+	got = c.CallRaw(`{"jsonrpc":"2.0","id":2,"method":"eth_blockNumber","params":[]}`)
+	require.Equal(t, `{"jsonrpc":"2.0","id":2,"result":"0x1"}`, got)
+}
+
+func TestCallRawErrorKeepsID(t *testing.T) {
+	c, err := NewClient(nil, params.UpstreamRPCConfig{})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name string
+		body string
+		id   string
+		err  string
+	}{
+		{
+			"params_not_array",
+			`{"jsonrpc":"2.0","id":"abc","method":"eth_getBalance","params":{"address":"0x0"}}`,
+			`"abc"`,
+			"json: cannot unmarshal object into Go value of type []interface {}",
+		},
+		{
+			"method_not_string",
+			`{"jsonrpc":"2.0","id":7,"method":123}`,
+			`7`,
+			"json: cannot unmarshal number into Go struct field jsonrpcRequest.method of type string",
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			expected := fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32700,"message":"%s"}}`, test.id, test.err)
+			require.Equal(t, expected, c.CallRaw(test.body))
+		})
+	}
+}
+
+func TestCallRawTimeoutUpstream(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+		fmt.Fprintln(w, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	c, err := NewClient(nil, params.UpstreamRPCConfig{Enabled: true, URL: ts.URL})
+	require.NoError(t, err)
+	c.SetCallRawTimeout(20 * time.Millisecond)
+
+	got := c.CallRaw(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"eth_blockNumber call timed out after 20ms"}}`, got)
+}
+
+func TestCallRawContextCanceled(t *testing.T) {
+	c, err := NewClient(nil, params.UpstreamRPCConfig{})
+	require.NoError(t, err)
+
+	c.RegisterHandler("eth_syncing", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	got := c.CallRawContext(ctx, `{"jsonrpc":"2.0","id":1,"method":"eth_syncing","params":[]}`)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"context canceled"}}`, got)
+}
+
+func TestCallRawContextUpstreamDeadline(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+		fmt.Fprintln(w, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`)
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	c, err := NewClient(nil, params.UpstreamRPCConfig{Enabled: true, URL: ts.URL})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	got := c.CallRawContext(ctx, `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"context deadline exceeded"}}`, got)
+}
+
+func TestCallRawContextDeadlineShorterThanTimeout(t *testing.T) {
+	c, err := NewClient(nil, params.UpstreamRPCConfig{})
+	require.NoError(t, err)
+
+	c.RegisterHandler("eth_syncing", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	c.SetCallRawTimeout(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	got := c.CallRawContext(ctx, `{"jsonrpc":"2.0","id":1,"method":"eth_syncing","params":[]}`)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"context deadline exceeded"}}`, got)
+}
+
+func TestCallRawResultValidator(t *testing.T) {
+	c, err := NewClient(nil, params.UpstreamRPCConfig{})
+	require.NoError(t, err)
+
+	code := "0x"
+	c.RegisterHandler("eth_getCode", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		return code, nil
+	})
+	c.SetResultValidator("eth_getCode", func(result json.RawMessage) error {
+		var code string
+		if err := json.Unmarshal(result, &code); err != nil {
+			return err
+		}
+		if code == "0x" {
+			return errors.New("empty code")
+		}
+		return nil
+	})
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"eth_getCode","params":["0x01","latest"]}`
+
+	got := c.CallRaw(body)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"invalid eth_getCode result: empty code"}}`, got)
+
+	code = "0x6001"
+	got = c.CallRaw(body)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"0x6001"}`, got)
+
+	c.SetResultValidator("eth_getCode", nil)
+	code = "0x"
+	got = c.CallRaw(body)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"0x"}`, got)
+}
+
 func TestIsBatch(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -146,3 +390,103 @@ func TestIsBatch(t *testing.T) {
 		})
 	}
 }
+
+func TestCallRawBatchConnectionLost(t *testing.T) {
+	c, err := NewClient(nil, params.UpstreamRPCConfig{})
+	require.NoError(t, err)
+
+	calls := 0
+	c.RegisterHandler("eth_blockNumber", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		calls++
+		if calls == 2 {
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+		}
+		return "0x1", nil
+	})
+
+	c.SetStrictJSONRPCVersion(true)
+
+	body := `[
+		{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]},
+		{"jsonrpc":"2.0","id":2,"method":"eth_blockNumber","params":[]},
+		{"jsonrpc":"2.0","id":3,"method":"eth_blockNumber","params":[]},
+		{"jsonrpc":"2.0","id":4,"method":"eth_blockNumber","params":[]},
+		{"jsonrpc":"2.0","id":5,"method":"eth_blockNumber","params":{}},
+		{"jsonrpc":"1.0","id":6,"method":"eth_blockNumber","params":[]}
+	]`
+
+	var responses []json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(c.CallRaw(body)), &responses))
+	require.Len(t, responses, 6)
+	require.Equal(t, 2, calls)
+
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`, string(responses[0]))
+	for i := 1; i < 4; i++ {
+		expected := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"error":{"code":-32603,"message":"%s"}}`, i+1, ErrConnectionLost)
+		require.Equal(t, expected, string(responses[i]))
+	}
+	// invalid requests get their usual errors
+	require.Equal(t, `{"jsonrpc":"2.0","id":5,"error":{"code":-32700,"message":"json: cannot unmarshal object into Go value of type []interface {}"}}`, string(responses[4]))
+	require.Equal(t, `{"jsonrpc":"2.0","id":6,"error":{"code":-32600,"message":"invalid request: jsonrpc must be \"2.0\""}}`, string(responses[5]))
+}
+
+func TestCallRawBatchTimeoutIsNotConnectionLost(t *testing.T) {
+	var requests int32
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		var req jsonrpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Method == "eth_syncing" {
+			<-done
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x1"}`, req.ID)
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	c, err := NewClient(nil, params.UpstreamRPCConfig{Enabled: true, URL: ts.URL})
+	require.NoError(t, err)
+	c.SetCallRawTimeout(20 * time.Millisecond)
+
+	body := `[
+		{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]},
+		{"jsonrpc":"2.0","id":2,"method":"eth_syncing","params":[]},
+		{"jsonrpc":"2.0","id":3,"method":"eth_blockNumber","params":[]}
+	]`
+
+	var responses []json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(c.CallRaw(body)), &responses))
+	require.Len(t, responses, 3)
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`, string(responses[0]))
+	require.Equal(t, `{"jsonrpc":"2.0","id":2,"error":{"code":-32603,"message":"eth_syncing call timed out after 20ms"}}`, string(responses[1]))
+	require.Equal(t, `{"jsonrpc":"2.0","id":3,"result":"0x1"}`, string(responses[2]))
+}
+
+func TestIsConnectionError(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"nil", nil, false},
+		{"client_quit", gethrpc.ErrClientQuit, true},
+		{"op_error", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"url_error", &url.Error{Op: "Post", URL: "http://localhost", Err: errors.New("connection refused")}, true},
+		{"url_deadline", &url.Error{Op: "Post", URL: "http://localhost", Err: context.DeadlineExceeded}, false},
+		{"url_canceled", &url.Error{Op: "Post", URL: "http://localhost", Err: context.Canceled}, false},
+		{"rpc_error", errors.New("execution reverted"), false},
+	}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, isConnectionError(test.err))
+		})
+	}
+}
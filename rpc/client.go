@@ -24,11 +24,15 @@ const (
 // List of RPC client errors.
 var (
 	ErrMethodNotFound = fmt.Errorf("The method does not exist/is not available")
+	ErrConnectionLost = fmt.Errorf("connection lost")
 )
 
 // Handler defines handler for RPC methods.
 type Handler func(context.Context, ...interface{}) (interface{}, error)
 
+// ResultValidator defines validator for results of RPC methods.
+type ResultValidator func(json.RawMessage) error
+
 // Client represents RPC client with custom routing
 // scheme. It automatically decides where RPC call
 // goes - Upstream or Local node.
@@ -38,6 +42,9 @@ type Client struct {
 	upstreamEnabled bool
 	upstreamURL     string
 
+	strictVersion  bool          // reject CallRaw requests with jsonrpc other than "2.0"
+	callRawTimeout time.Duration // limits every call made via CallRaw, zero means no limit
+
 	local    *gethrpc.Client
 	upstream *gethrpc.Client
 
@@ -45,7 +52,11 @@ type Client struct {
 
 	handlersMx sync.RWMutex       // mx guards handlers
 	handlers   map[string]Handler // locally registered handlers
-	log        log.Logger
+
+	validatorsMx sync.RWMutex               // mx guards validators
+	validators   map[string]ResultValidator // result validators for CallRaw
+
+	log log.Logger
 }
 
 // NewClient initializes Client and tries to connect to both,
@@ -55,9 +66,10 @@ type Client struct {
 // reconnect to the server if connection is lost.
 func NewClient(client *gethrpc.Client, upstream params.UpstreamRPCConfig) (*Client, error) {
 	c := Client{
-		local:    client,
-		handlers: make(map[string]Handler),
-		log:      log.New("package", "status-go/rpc.Client"),
+		local:      client,
+		handlers:   make(map[string]Handler),
+		validators: make(map[string]ResultValidator),
+		log:        log.New("package", "status-go/rpc.Client"),
 	}
 
 	var err error
@@ -103,6 +115,25 @@ func (c *Client) UpdateUpstreamURL(url string) error {
 	return nil
 }
 
+// SetStrictJSONRPCVersion enables or disables checking of the jsonrpc field
+// of requests made via CallRaw. In strict mode, requests with the field missing
+// or not equal to "2.0" are rejected with an invalid request error.
+// It is disabled by default.
+func (c *Client) SetStrictJSONRPCVersion(strict bool) {
+	c.Lock()
+	c.strictVersion = strict
+	c.Unlock()
+}
+
+// SetCallRawTimeout sets the maximum duration of every single call made via CallRaw,
+// including each request of a batch. Calls exceeding it are answered with
+// an internal error response. Zero timeout, the default, disables the limit.
+func (c *Client) SetCallRawTimeout(timeout time.Duration) {
+	c.Lock()
+	c.callRawTimeout = timeout
+	c.Unlock()
+}
+
 // Call performs a JSON-RPC call with the given arguments and unmarshals into
 // result if no error occurred.
 //
@@ -164,8 +195,10 @@ func (c *Client) CallContextIgnoringLocalHandlers(ctx context.Context, result in
 
 // RegisterHandler registers local handler for specific RPC method.
 //
-// If method is registered, it will be executed with given handler and
-// never routed to the upstream or local servers.
+// If method is registered, it will be executed with given handler instead
+// of being routed to the upstream or local servers. The handler can still
+// pass the call on to the node with CallContextIgnoringLocalHandlers,
+// e.g. to answer only some of the calls locally.
 func (c *Client) RegisterHandler(method string, handler Handler) {
 	c.handlersMx.Lock()
 	defer c.handlersMx.Unlock()
@@ -173,6 +206,38 @@ func (c *Client) RegisterHandler(method string, handler Handler) {
 	c.handlers[method] = handler
 }
 
+// SetResultValidator sets validator for results of specific RPC method
+// called via CallRaw. If validator returns an error, the call is answered
+// with an internal error instead of the result.
+// Nil validator removes the one set for the method.
+func (c *Client) SetResultValidator(method string, validator ResultValidator) {
+	c.validatorsMx.Lock()
+	defer c.validatorsMx.Unlock()
+
+	if validator == nil {
+		delete(c.validators, method)
+		return
+	}
+	c.validators[method] = validator
+}
+
+// validateResult validates result of method with validator set for it, if any.
+func (c *Client) validateResult(method string, result json.RawMessage) error {
+	c.validatorsMx.RLock()
+	validator, ok := c.validators[method]
+	c.validatorsMx.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	if err := validator(result); err != nil {
+		return fmt.Errorf("invalid %s result: %v", method, err)
+	}
+
+	return nil
+}
+
 // callMethod calls registered RPC handler with given args and pointer to result.
 // It handles proper params and result converting
 //
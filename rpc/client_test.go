@@ -2,9 +2,11 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -101,6 +103,46 @@ func TestUpdateUpstreamURL(t *testing.T) {
 	require.Equal(t, updatedUpstreamTs.URL, c.upstreamURL)
 }
 
+func TestLocalHandlerFallback(t *testing.T) {
+	var nodeCalls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&nodeCalls, 1)
+
+		var req jsonrpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0xnode"}`, req.ID)
+	}))
+	defer ts.Close()
+
+	gethRPCClient, err := gethrpc.Dial(ts.URL)
+	require.NoError(t, err)
+
+	c, err := NewClient(gethRPCClient, params.UpstreamRPCConfig{Enabled: false, URL: ""})
+	require.NoError(t, err)
+
+	// answer calls to a single contract locally, pass everything else to the node
+	c.RegisterHandler("eth_call", func(ctx context.Context, args ...interface{}) (interface{}, error) {
+		if call, ok := args[0].(map[string]interface{}); ok && call["to"] == "0x01" {
+			return "0xlocal", nil
+		}
+
+		var result json.RawMessage
+		err := c.CallContextIgnoringLocalHandlers(ctx, &result, "eth_call", args...)
+		return result, err
+	})
+
+	got := c.CallRaw(`{"jsonrpc":"2.0","id":1,"method":"eth_call","params":[{"to":"0x01","data":"0x"},"latest"]}`)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"result":"0xlocal"}`, got)
+	require.Equal(t, int32(0), atomic.LoadInt32(&nodeCalls))
+
+	got = c.CallRaw(`{"jsonrpc":"2.0","id":2,"method":"eth_call","params":[{"to":"0x02","data":"0x"},"latest"]}`)
+	require.Equal(t, `{"jsonrpc":"2.0","id":2,"result":"0xnode"}`, got)
+	require.Equal(t, int32(1), atomic.LoadInt32(&nodeCalls))
+}
+
 func createTestServer(resp string) *httptest.Server {
 	if resp == "" {
 		resp = `{